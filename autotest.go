@@ -36,14 +36,37 @@ type autotest struct {
 	// IgnoreFiles is a list of regular expression patterns for files that should be ignored.
 	IgnoreFiles []*regexp.Regexp
 
+	// IgnorePaths is a list of regular expression patterns matched against full
+	// paths; matching directories are never watched, and events under them are dropped.
+	IgnorePaths []*regexp.Regexp
+
+	// IsExcludedPath, when set, is consulted alongside IgnorePaths to decide
+	// whether a path should be excluded from watching entirely.
+	IsExcludedPath func(string) bool
+
 	// TestFlags contains optional arguments for 'go test'.
 	TestFlags []string
 
+	// Reporter receives the stream of test events from 'go test -json' runs.
+	Reporter Reporter
+
+	// MaxPendingEvents caps the number of distinct changed paths coalesced
+	// between runs; once exceeded, an immediate full run is scheduled instead
+	// of waiting for the queue to settle. Zero means unbounded.
+	MaxPendingEvents int
+
 	debug       bool
 	fs          *fsnotify.Watcher
 	done        chan bool
 	gosrc       string
+	moduleRoot  string
+	modulePath  string
 	paths       []string
+	roots       []string
+	keys        <-chan byte
+	dirty       map[string]time.Time
+	allMode     bool
+	also        string
 	timeSuccess time.Time
 	timeFailure time.Time
 	lastState   int
@@ -76,13 +99,16 @@ func newWatcher() (*autotest, error) {
 		IgnoreFiles: []*regexp.Regexp{
 			regexp.MustCompile(`\..*\.swp$`),
 		},
-		TestFlags: make([]string, 0),
-		debug:     false,
-		fs:        fs,
-		done:      make(chan bool),
-		gosrc:     filepath.Join(os.Getenv("GOPATH"), "src"),
-		paths:     make([]string, 0),
-		lastState: starting,
+		TestFlags:        make([]string, 0),
+		Reporter:         NewConsoleReporter(),
+		MaxPendingEvents: 200,
+		debug:            false,
+		fs:               fs,
+		done:             make(chan bool),
+		gosrc:            filepath.Join(os.Getenv("GOPATH"), "src"),
+		paths:            make([]string, 0),
+		dirty:            make(map[string]time.Time),
+		lastState:        starting,
 	}
 	return a, nil
 }
@@ -92,11 +118,12 @@ func (a *autotest) Close() error {
 }
 
 func (a *autotest) Start() {
+	a.keys = startKeyReader(a.done)
 	go a.monitorChanges()
 }
 
 func (a *autotest) Stop() {
-	a.done <- true
+	close(a.done)
 }
 
 func (a *autotest) Add(path string) error {
@@ -129,6 +156,20 @@ func (a *autotest) Remove(path string) error {
 	return a.fs.Remove(path)
 }
 
+// excluded reports whether path should be skipped entirely, via IsExcludedPath
+// or any of the IgnorePaths patterns.
+func (a *autotest) excluded(path string) bool {
+	if a.IsExcludedPath != nil && a.IsExcludedPath(path) {
+		return true
+	}
+	for _, re := range a.IgnorePaths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
 // AddRecursive walks a directory recursively, and watches all subdirectories.
 func (a *autotest) AddRecursive(path string) error {
 	return filepath.Walk(path, func(subpath string, info os.FileInfo, err error) error {
@@ -139,13 +180,17 @@ func (a *autotest) AddRecursive(path string) error {
 			return filepath.SkipDir
 		}
 		if info.IsDir() {
+			if a.excluded(subpath) {
+				return filepath.SkipDir
+			}
 			return a.Add(subpath)
 		}
 		return nil
 	})
 }
 
-// RunTests invokes the 'go test' tool for all monitored packages.
+// RunTests invokes the 'go test' tool for the packages touched since the
+// last run (or every watched package, in --all mode).
 func (a *autotest) RunTests() {
 	if err := a.handleModifications(); err != nil {
 		msg := "error: " + err.Error()
@@ -169,12 +214,17 @@ func (a *autotest) RunTests() {
 		if len(msg) != 0 {
 			log.Println("\u001b[32m" + msg + "\u001b[0m")
 		}
+		a.dirty = make(map[string]time.Time)
 	}
 }
 
-// monitorChanges is the main processing loop for file system notifications.
+// monitorChanges is the main processing loop for file system notifications
+// and interactive watch-mode keybindings.
 func (a *autotest) monitorChanges() {
-	modified := false
+	pending := make(map[string]bool)
+	timer := time.NewTimer(a.SettleTime)
+	stopTimer(timer)
+
 	for {
 		select {
 		case <-a.done:
@@ -182,30 +232,88 @@ func (a *autotest) monitorChanges() {
 			return
 
 		case err := <-a.fs.Errors:
+			if err == fsnotify.ErrEventOverflow {
+				// The kernel-level event queue overflowed, so some changes
+				// were lost; rebuild watcher state and run everything.
+				log.Println("event queue overflow, rescanning watched directories")
+				if rerr := a.rescan(); rerr != nil {
+					log.Println("error rescanning after overflow:", rerr)
+				}
+				pending = make(map[string]bool)
+				stopTimer(timer)
+				a.runAllLogged()
+				continue
+			}
 			log.Println("error:", err)
 
 		case event := <-a.fs.Events:
 			mod, err := a.handleEvent(event)
 			if err != nil {
 				log.Println("error:", err)
-			} else if mod {
-				modified = true
+				continue
+			}
+			if !mod {
+				continue
+			}
+			pending[filepath.Clean(event.Name)] = true
+			if a.MaxPendingEvents > 0 && len(pending) > a.MaxPendingEvents {
+				log.Printf("pending event set exceeded %d entries, running now\n", a.MaxPendingEvents)
+				pending = make(map[string]bool)
+				stopTimer(timer)
+				a.runAllLogged()
+				continue
+			}
+			stopTimer(timer)
+			timer.Reset(a.SettleTime)
+
+		case key, ok := <-a.keys:
+			if !ok {
+				continue
+			}
+			switch key {
+			case 'r':
+				a.RunTests()
+			case 'd':
+				if err := a.runDebug(); err != nil {
+					log.Println("debug run error:", err)
+				}
+			case 'a':
+				a.runAllLogged()
+			case 'l':
+				if err := a.rescan(); err != nil {
+					log.Println("rescan error:", err)
+				}
 			}
 
-		case <-time.After(a.SettleTime):
-			if modified {
+		case <-timer.C:
+			if len(pending) > 0 {
 				a.RunTests()
-				modified = false
+				pending = make(map[string]bool)
 			}
 		}
 	}
 }
 
+// stopTimer stops t, draining its channel if it had already fired, so it can
+// be safely reused with Reset.
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
+
 // handleEvent handles a file system change notification.
 func (a *autotest) handleEvent(event fsnotify.Event) (bool, error) {
 	filename := event.Name
 	modified := false
 
+	if a.excluded(filename) {
+		return false, nil
+	}
+
 	if event.Op&fsnotify.Create != 0 {
 		info, err := os.Stat(filename)
 		if err != nil {
@@ -216,6 +324,9 @@ func (a *autotest) handleEvent(event fsnotify.Event) (bool, error) {
 			return false, err
 		}
 		if info.IsDir() {
+			if a.excluded(filename) {
+				return false, nil
+			}
 			if err := a.Add(filename); err != nil {
 				return false, err
 			}
@@ -259,36 +370,191 @@ func (a *autotest) handleEvent(event fsnotify.Event) (bool, error) {
 			modified = true
 		}
 	}
+	if modified {
+		if pkg := a.getPackageName(filepath.Dir(filename)); pkg != "" {
+			a.dirty[pkg] = time.Now()
+		}
+	}
 	return modified, nil
 }
 
-// handleModifications launches 'go test'.
+// handleModifications launches 'go test' for the dirty packages (or every
+// watched package, in --all mode).
 func (a *autotest) handleModifications() error {
-	args := make([]string, 1+len(a.TestFlags))
-	args[0] = "test"
-	copy(args[1:], a.TestFlags)
-	npkg := 0
+	_, args := a.testCommand()
+	return a.runJSONTest(args)
+}
+
+// runJSONTest runs 'go test -json' with args, streaming the decoded event
+// objects to a.Reporter as they arrive.
+func (a *autotest) runJSONTest(args []string) error {
+	args = append(args, "-json")
+	cmd := exec.Command("go", args...)
+	cmd.Dir = a.moduleRoot
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = os.Stderr
+	log.Printf("running go %s\n", strings.Join(args, " "))
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if err := decodeEvents(stdout, a.Reporter); err != nil {
+		log.Println("error decoding test output:", err)
+	}
+	return cmd.Wait()
+}
+
+// testCommand builds the command and arguments for a regular 'go test' run.
+// In --all mode it covers every watched package; otherwise it covers only
+// the packages marked dirty since the last run.
+func (a *autotest) testCommand() (string, []string) {
+	args := make([]string, 0, 2+len(a.TestFlags)+len(a.paths))
+	args = append(args, "test")
+	args = append(args, a.TestFlags...)
+	if a.allMode {
+		for _, path := range a.paths {
+			if pkg := a.getPackageName(path); pkg != "" {
+				args = append(args, a.selector(pkg))
+			}
+		}
+	} else {
+		for pkg := range a.dirty {
+			args = append(args, a.selector(pkg))
+		}
+	}
+	if a.also != "" {
+		args = append(args, a.also)
+	}
+	return "go", args
+}
+
+// runDebug re-runs the last test command under 'dlv test' so breakpoints hit.
+func (a *autotest) runDebug() error {
+	pkg := a.debugPackage()
+	if pkg == "" {
+		return fmt.Errorf("no package to debug")
+	}
+	args := []string{"test", a.selector(pkg)}
+	if len(a.TestFlags) > 0 {
+		args = append(args, "--")
+		args = append(args, a.TestFlags...)
+	}
+	return a.runCommand("dlv", args)
+}
+
+// debugPackage picks the single package to hand to 'dlv test', which (unlike
+// 'go test') accepts only one: the first dirty package if any, otherwise the
+// first watched package.
+func (a *autotest) debugPackage() string {
+	if len(a.dirty) > 1 {
+		log.Println("multiple packages are dirty; debugging only the first one")
+	}
+	for pkg := range a.dirty {
+		return pkg
+	}
 	for _, path := range a.paths {
 		if pkg := a.getPackageName(path); pkg != "" {
-			args = append(args, pkg)
-			npkg++
+			return pkg
 		}
 	}
-	cmd := exec.Command("go", args...)
+	return ""
+}
+
+// runAll invokes 'go test ./...' for every watched root, regardless of which
+// packages have recently changed.
+func (a *autotest) runAll() error {
+	args := make([]string, 0, 1+len(a.TestFlags)+len(a.roots))
+	args = append(args, "test")
+	args = append(args, a.TestFlags...)
+	for _, root := range a.roots {
+		if pkg := a.getPackageName(root); pkg != "" {
+			args = append(args, a.selector(pkg)+"/...")
+		}
+	}
+	err := a.runCommand("go", args)
+	if err == nil {
+		a.dirty = make(map[string]time.Time)
+	}
+	return err
+}
+
+// runAllLogged is runAll with errors logged rather than returned, for use
+// from the watch-mode keybinding.
+func (a *autotest) runAllLogged() {
+	if err := a.runAll(); err != nil {
+		log.Println("error:", err)
+	}
+}
+
+// rescan re-walks the original watched roots via AddRecursive, picking up
+// newly created packages and dropping removed ones.
+func (a *autotest) rescan() error {
+	for _, root := range a.roots {
+		stale := make([]string, 0)
+		for _, p := range a.paths {
+			if p == root || strings.HasPrefix(p, root+string(filepath.Separator)) {
+				stale = append(stale, p)
+			}
+		}
+		for _, p := range stale {
+			if err := a.Remove(p); err != nil {
+				return err
+			}
+		}
+		if err := a.AddRecursive(root); err != nil {
+			return err
+		}
+	}
+	log.Println("rescanned watched directories")
+	return nil
+}
+
+// runCommand executes name with args, forwarding its output to the console.
+func (a *autotest) runCommand(name string, args []string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = a.moduleRoot
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	log.Printf("running go test with %d packages\n", npkg)
+	log.Printf("running %s %s\n", name, strings.Join(args, " "))
 	return cmd.Run()
 }
 
-// getPackageName returns the go package name for a path, or "" if not a package dir.
+// getPackageName returns the go import path for a path, or "" if not a
+// package dir. Inside a module it is derived from go.mod's module directive;
+// otherwise it falls back to the path's location under $GOPATH/src.
 func (a *autotest) getPackageName(path string) string {
+	if a.moduleRoot != "" {
+		rel, err := filepath.Rel(a.moduleRoot, path)
+		if err != nil {
+			return ""
+		}
+		if rel == "." {
+			return a.modulePath
+		}
+		return a.modulePath + "/" + filepath.ToSlash(rel)
+	}
 	if pkg, err := filepath.Rel(a.gosrc, path); err == nil {
 		return pkg
 	}
 	return ""
 }
 
+// selector returns the 'go test' argument for pkg: a path relative to the
+// module root (e.g. "./sub") when running in module mode, since 'go test' is
+// invoked from moduleRoot, or the bare import path otherwise.
+func (a *autotest) selector(pkg string) string {
+	if a.moduleRoot == "" || a.modulePath == "" {
+		return pkg
+	}
+	rel := strings.TrimPrefix(pkg, a.modulePath)
+	if rel == "" {
+		return "."
+	}
+	return "." + rel
+}
+
 // --------------------------------------------------------------------------
 
 func getCwd() string {
@@ -330,18 +596,25 @@ func main() {
 usage: %s [-h | --help] [testflags] [path...] [package...]
 
 options:
-  -h, --help   print this message
-  testflags    flags supported by 'go test'; see 'go help testflag'
-  path...      filesystem path, monitored recursively
-  package...   go package name for which 'go test' will be issued
+  -h, --help        print this message
+  -a, --all         always run every watched package, instead of only dirty ones
+  --also=PKG        always include PKG (e.g. ./...) alongside the dirty set
+  --events-file=PATH also write each test event as a JSON line to PATH
+  --ignore-path=RE  regular expression matching paths to exclude (repeatable)
+  --ignore-dir=NAME directory name to exclude, e.g. vendor (repeatable)
+  testflags         flags supported by 'go test'; see 'go help testflag'
+  path...           filesystem path, monitored recursively
+  package...        go package name for which 'go test' will be issued
+
+while running, these keys are read from the terminal:
+  r   re-run the last test command
+  d   re-run the last test command under 'dlv test'
+  a   run 'go test ./...' across all watched roots
+  l   re-scan the watched directories for added/removed packages
 `, os.Args[0])
 			os.Exit(0)
 		}
 	}
-	if os.Getenv("GOPATH") == "" {
-		log.Fatalln("GOPATH is not set")
-	}
-
 	w, err := newWatcher()
 	if err != nil {
 		log.Fatal(err)
@@ -361,13 +634,46 @@ options:
 	// monitor paths
 	gotOne := false
 	for _, arg := range os.Args[1:] {
+		switch {
+		case arg == "-a" || arg == "--all":
+			w.allMode = true
+			continue
+		case strings.HasPrefix(arg, "--also="):
+			w.also = strings.TrimPrefix(arg, "--also=")
+			continue
+		case strings.HasPrefix(arg, "--events-file="):
+			f, err := os.Create(strings.TrimPrefix(arg, "--events-file="))
+			if err != nil {
+				log.Fatalln("cannot create events file:", err)
+			}
+			w.Reporter = multiReporter{w.Reporter, NewJSONLReporter(f)}
+			continue
+		case strings.HasPrefix(arg, "--ignore-path="):
+			pattern := strings.TrimPrefix(arg, "--ignore-path=")
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				log.Fatalln("invalid --ignore-path pattern:", err)
+			}
+			w.IgnorePaths = append(w.IgnorePaths, re)
+			continue
+		case strings.HasPrefix(arg, "--ignore-dir="):
+			w.IgnoreDirs[strings.TrimPrefix(arg, "--ignore-dir=")] = true
+			continue
+		}
 		if arg[0] == '-' {
 			w.TestFlags = append(w.TestFlags, arg)
 		} else if path := findPackage(arg); path != "" {
+			if w.moduleRoot == "" {
+				if root, modPath, ok := findModuleRoot(path); ok {
+					w.moduleRoot = root
+					w.modulePath = modPath
+				}
+			}
 			if err := w.AddRecursive(path); err != nil {
 				log.Fatal(err)
 			} else {
 				gotOne = true
+				w.roots = append(w.roots, path)
 			}
 		}
 	}
@@ -377,7 +683,9 @@ options:
 	}
 
 	w.Start()
-	w.RunTests()
+	// Nothing has been marked dirty yet on the first run, so cover every
+	// watched package instead of running bare 'go test' with no selectors.
+	w.runAllLogged()
 	<-w.Finished
 	if err := w.Close(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-fsnotify/fsnotify"
+)
+
+func TestSelectorModuleMode(t *testing.T) {
+	a := &autotest{moduleRoot: "/src/repo", modulePath: "github.com/roastery/autotest"}
+
+	cases := []struct {
+		pkg  string
+		want string
+	}{
+		{"github.com/roastery/autotest", "."},
+		{"github.com/roastery/autotest/sub", "./sub"},
+		{"github.com/roastery/autotest/sub/deeper", "./sub/deeper"},
+	}
+	for _, c := range cases {
+		if got := a.selector(c.pkg); got != c.want {
+			t.Errorf("selector(%q) = %q, want %q", c.pkg, got, c.want)
+		}
+	}
+}
+
+func TestSelectorGopathMode(t *testing.T) {
+	a := &autotest{}
+	const pkg = "github.com/roastery/autotest/sub"
+	if got := a.selector(pkg); got != pkg {
+		t.Errorf("selector(%q) = %q, want %q (unchanged, outside module mode)", pkg, got, pkg)
+	}
+}
+
+// TestRescanDoesNotStripSiblingRoot is a regression test for a rescan() bug
+// where a bare strings.HasPrefix comparison treated a root's sibling
+// directory sharing a literal string prefix (here "pkg" and "pkg2") as
+// belonging to that root, so rescanning "pkg" would remove the watch on
+// "pkg2" without anything re-adding it.
+func TestRescanDoesNotStripSiblingRoot(t *testing.T) {
+	base := t.TempDir()
+	pkgDir := filepath.Join(base, "pkg")
+	pkg2Dir := filepath.Join(base, "pkg2")
+	for _, dir := range []string{pkgDir, pkg2Dir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("MkdirAll(%s): %v", dir, err)
+		}
+	}
+
+	a, err := newWatcher()
+	if err != nil {
+		t.Fatalf("newWatcher: %v", err)
+	}
+	defer a.Close()
+
+	// pkg2 is watched (and becomes a root) before pkg, so that when rescan
+	// later processes pkg it's comparing against an already-established
+	// sibling watch on pkg2 rather than one about to be (re-)added.
+	a.roots = []string{pkg2Dir, pkgDir}
+	if err := a.AddRecursive(pkg2Dir); err != nil {
+		t.Fatalf("AddRecursive(pkg2): %v", err)
+	}
+	if err := a.AddRecursive(pkgDir); err != nil {
+		t.Fatalf("AddRecursive(pkg): %v", err)
+	}
+
+	if err := a.rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+
+	if !contains(a.paths, pkg2Dir) {
+		t.Errorf("rescan dropped the watch on sibling root %s: paths = %v", pkg2Dir, a.paths)
+	}
+	if !contains(a.paths, pkgDir) {
+		t.Errorf("rescan dropped the watch on %s: paths = %v", pkgDir, a.paths)
+	}
+}
+
+func contains(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// TestMonitorChangesCoalescesBurstEvents verifies that a burst of write
+// events arriving within SettleTime results in exactly one 'go test'
+// invocation, not one per event.
+func TestMonitorChangesCoalescesBurstEvents(t *testing.T) {
+	dir := t.TempDir()
+	countFile := filepath.Join(dir, "invocations")
+	fakeGo := filepath.Join(dir, "go")
+	script := "#!/bin/sh\necho x >> " + countFile + "\necho '{\"Action\":\"pass\"}'\n"
+	if err := os.WriteFile(fakeGo, []byte(script), 0755); err != nil {
+		t.Fatalf("writing fake go: %v", err)
+	}
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+oldPath)
+	defer os.Setenv("PATH", oldPath)
+
+	watched := filepath.Join(dir, "pkg")
+	if err := os.MkdirAll(watched, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	a, err := newWatcher()
+	if err != nil {
+		t.Fatalf("newWatcher: %v", err)
+	}
+	defer a.Close()
+	a.SettleTime = 20 * time.Millisecond
+	a.moduleRoot = dir
+	a.modulePath = "example.com/fixture"
+	if err := a.AddRecursive(watched); err != nil {
+		t.Fatalf("AddRecursive: %v", err)
+	}
+
+	go a.monitorChanges()
+	defer a.Stop()
+
+	file := filepath.Join(watched, "foo.go")
+	for i := 0; i < 5; i++ {
+		a.fs.Events <- fsnotify.Event{Name: file, Op: fsnotify.Write}
+	}
+
+	time.Sleep(5 * a.SettleTime)
+
+	data, err := os.ReadFile(countFile)
+	if err != nil {
+		t.Fatalf("reading invocation count: %v", err)
+	}
+	if got := len(strings.Fields(string(data))); got != 1 {
+		t.Errorf("'go test' ran %d times for one coalesced burst, want 1", got)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// ConsoleReporter prints a colorized one-line summary per package as results
+// stream in, plus a final pass/fail/skip tally. Output text is buffered per
+// test (or per package, for output with no test name) and only printed if
+// that test or package ends up failing.
+type ConsoleReporter struct {
+	passed, failed, skipped int
+	output                  map[string][]string
+}
+
+// NewConsoleReporter returns a Reporter that writes to the standard logger.
+func NewConsoleReporter() *ConsoleReporter {
+	return &ConsoleReporter{output: make(map[string][]string)}
+}
+
+func outputKey(pkg, test string) string {
+	return pkg + "\x00" + test
+}
+
+func (c *ConsoleReporter) TestStarted(pkg, test string) {}
+
+func (c *ConsoleReporter) TestOutput(pkg, test, text string) {
+	key := outputKey(pkg, test)
+	c.output[key] = append(c.output[key], text)
+}
+
+func (c *ConsoleReporter) TestResult(pkg, test, action string, elapsed float64) {
+	key := outputKey(pkg, test)
+	switch action {
+	case "pass":
+		c.passed++
+		delete(c.output, key)
+	case "fail":
+		c.failed++
+		log.Printf("\u001b[31mFAIL\u001b[0m %s %s (%.2fs)\n", pkg, test, elapsed)
+		c.dumpOutput(key)
+	case "skip":
+		c.skipped++
+		delete(c.output, key)
+	}
+}
+
+func (c *ConsoleReporter) PackageResult(pkg, action string, elapsed float64) {
+	key := outputKey(pkg, "")
+	switch action {
+	case "pass":
+		log.Printf("\u001b[32mok\u001b[0m   %s (%.2fs)\n", pkg, elapsed)
+		delete(c.output, key)
+	case "fail":
+		log.Printf("\u001b[31mFAIL\u001b[0m %s (%.2fs)\n", pkg, elapsed)
+		c.dumpOutput(key)
+	}
+}
+
+// dumpOutput prints and discards the buffered output for key.
+func (c *ConsoleReporter) dumpOutput(key string) {
+	for _, line := range c.output[key] {
+		fmt.Print(line)
+	}
+	delete(c.output, key)
+}
+
+func (c *ConsoleReporter) RunFinished() {
+	log.Printf("%d passed, %d failed, %d skipped\n", c.passed, c.failed, c.skipped)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureOutput redirects both the standard logger and os.Stdout for the
+// duration of fn, returning everything written to either.
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	var logBuf bytes.Buffer
+	prevOut := log.Writer()
+	prevFlags := log.Flags()
+	log.SetOutput(&logBuf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(prevOut)
+		log.SetFlags(prevFlags)
+	}()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	prevStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = prevStdout }()
+
+	fn()
+
+	w.Close()
+	var stdoutBuf bytes.Buffer
+	io.Copy(&stdoutBuf, r)
+
+	return logBuf.String() + stdoutBuf.String()
+}
+
+func TestConsoleReporterPrintsBufferedBuildFailureOutput(t *testing.T) {
+	rep := NewConsoleReporter()
+	const pkg = "github.com/roastery/autotest/pkgy"
+	const errLine = "pkgy/foo.go:4:9: undefined: undefinedThing"
+
+	got := captureOutput(t, func() {
+		if err := decodeEvents(strings.NewReader(buildFailureFixture), rep); err != nil {
+			t.Fatalf("decodeEvents: %v", err)
+		}
+	})
+
+	if !strings.Contains(got, errLine) {
+		t.Errorf("buffered compiler output never printed; got:\n%s", got)
+	}
+	if !strings.Contains(got, pkg) {
+		t.Errorf("FAIL summary for %s never printed; got:\n%s", pkg, got)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONLReporter writes each test event as a line of JSON to an underlying
+// writer, for downstream tooling or CI ingestion.
+type JSONLReporter struct {
+	enc *json.Encoder
+}
+
+// NewJSONLReporter returns a Reporter that writes newline-delimited JSON to w.
+func NewJSONLReporter(w io.Writer) *JSONLReporter {
+	return &JSONLReporter{enc: json.NewEncoder(w)}
+}
+
+func (j *JSONLReporter) TestStarted(pkg, test string) {
+	j.write("run", pkg, test, 0)
+}
+
+func (j *JSONLReporter) TestResult(pkg, test, action string, elapsed float64) {
+	j.write(action, pkg, test, elapsed)
+}
+
+func (j *JSONLReporter) TestOutput(pkg, test, text string) {
+	j.enc.Encode(testEvent{Action: "output", Package: pkg, Test: test, Output: text})
+}
+
+func (j *JSONLReporter) PackageResult(pkg, action string, elapsed float64) {
+	j.write(action, pkg, "", elapsed)
+}
+
+func (j *JSONLReporter) RunFinished() {}
+
+func (j *JSONLReporter) write(action, pkg, test string, elapsed float64) {
+	j.enc.Encode(testEvent{Action: action, Package: pkg, Test: test, Elapsed: elapsed})
+}
@@ -0,0 +1,50 @@
+// +build linux darwin
+
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"syscall"
+)
+
+// startKeyReader puts stdin into raw mode and streams single keypresses on
+// the returned channel for as long as the watcher runs, restoring the
+// terminal when stop is closed. If stdin isn't a terminal we can put into
+// raw mode, it returns a channel that never fires.
+func startKeyReader(stop <-chan bool) <-chan byte {
+	keys := make(chan byte)
+	fd := int(os.Stdin.Fd())
+
+	orig, err := tcGetAttr(fd)
+	if err != nil {
+		return keys
+	}
+	raw := orig
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := tcSetAttr(fd, &raw); err != nil {
+		return keys
+	}
+
+	go func() {
+		defer tcSetAttr(fd, &orig)
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			b, err := reader.ReadByte()
+			if err != nil {
+				log.Println("keyboard input closed:", err)
+				return
+			}
+			select {
+			case keys <- b:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return keys
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// parseModulePath extracts the module path from the 'module' directive of a
+// go.mod file.
+func parseModulePath(gomod string) (string, error) {
+	data, err := ioutil.ReadFile(gomod)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "module")), nil
+		}
+	}
+	return "", fmt.Errorf("no module directive in %s", gomod)
+}
+
+// findModuleRoot walks up from dir looking for a go.mod file, returning its
+// directory and declared module path. ok is false if dir isn't inside a module.
+func findModuleRoot(dir string) (root, modulePath string, ok bool) {
+	for {
+		gomod := filepath.Join(dir, "go.mod")
+		if modPath, err := parseModulePath(gomod); err == nil {
+			return dir, modPath, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
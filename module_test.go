@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+}
+
+func TestParseModulePath(t *testing.T) {
+	dir := t.TempDir()
+	gomod := filepath.Join(dir, "go.mod")
+	writeFile(t, gomod, "module github.com/roastery/autotest\n\ngo 1.21\n")
+
+	got, err := parseModulePath(gomod)
+	if err != nil {
+		t.Fatalf("parseModulePath: %v", err)
+	}
+	if want := "github.com/roastery/autotest"; got != want {
+		t.Errorf("parseModulePath = %q, want %q", got, want)
+	}
+}
+
+func TestParseModulePathMissingDirective(t *testing.T) {
+	dir := t.TempDir()
+	gomod := filepath.Join(dir, "go.mod")
+	writeFile(t, gomod, "go 1.21\n")
+
+	if _, err := parseModulePath(gomod); err == nil {
+		t.Fatal("expected an error for a go.mod with no module directive")
+	}
+}
+
+func TestFindModuleRoot(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/foo\n")
+	sub := filepath.Join(root, "sub", "pkg")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	gotRoot, gotPath, ok := findModuleRoot(sub)
+	if !ok {
+		t.Fatal("findModuleRoot: ok = false")
+	}
+	if gotRoot != root {
+		t.Errorf("root = %q, want %q", gotRoot, root)
+	}
+	if want := "example.com/foo"; gotPath != want {
+		t.Errorf("modulePath = %q, want %q", gotPath, want)
+	}
+}
+
+func TestFindModuleRootNotFound(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, ok := findModuleRoot(dir); ok {
+		t.Fatal("findModuleRoot: ok = true outside any module")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"regexp"
+	"time"
+)
+
+// testEvent mirrors one JSON object emitted by 'go test -json'; see 'go help
+// test' / the testing package's json output for the full field set.
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// Reporter receives the stream of test events produced by a 'go test -json' run.
+type Reporter interface {
+	TestStarted(pkg, test string)
+	TestResult(pkg, test, action string, elapsed float64)
+	TestOutput(pkg, test, text string)
+	PackageResult(pkg, action string, elapsed float64)
+	RunFinished()
+}
+
+// failLine matches the plain-text package summary 'go test -json' prints
+// outside its JSON encoding when a package fails to build, e.g.
+// "FAIL\tgithub.com/roastery/autotest/pkg [build failed]".
+var failLine = regexp.MustCompile(`^FAIL\t(\S+)[\t ](\[.+\])$`)
+
+// pkgHeaderLine matches the "# <pkg>" header 'go test' prints before the
+// compiler output for a package that failed to build. A vet-triggered
+// failure appends the test binary name, e.g. "# pkg [pkg.test]".
+var pkgHeaderLine = regexp.MustCompile(`^# (\S+)(?: \[.+\])?$`)
+
+// decodeEvents reads 'go test -json' output from r line by line and
+// dispatches each event to rep, calling rep.RunFinished() once r is
+// exhausted. A line that isn't valid JSON (go test falls back to plain text
+// for a package's build output and summary line on a build/setup failure) is
+// forwarded to rep.TestOutput under the package named by the most recent "#
+// <pkg>" header, so it's buffered under the same key a following "FAIL"
+// summary will flush. That "FAIL" summary is also synthesized into a
+// PackageResult so build failures still show up in the tally and any JSONL
+// sink.
+func decodeEvents(r io.Reader, rep Reporter) error {
+	seen := make(map[string]bool)
+	var currentPkg string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var ev testEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			text := string(line)
+			if m := pkgHeaderLine.FindStringSubmatch(text); m != nil {
+				currentPkg = m[1]
+			}
+			pkg := currentPkg
+			if m := failLine.FindStringSubmatch(text); m != nil {
+				pkg = m[1]
+				currentPkg = m[1]
+			}
+			rep.TestOutput(pkg, "", text)
+			if m := failLine.FindStringSubmatch(text); m != nil && !seen[m[1]] {
+				seen[m[1]] = true
+				rep.PackageResult(m[1], "fail", 0)
+			}
+			continue
+		}
+		if ev.Test == "" {
+			switch ev.Action {
+			case "pass", "fail", "skip":
+				seen[ev.Package] = true
+			}
+		}
+		dispatchEvent(ev, rep)
+	}
+	rep.RunFinished()
+	return scanner.Err()
+}
+
+func dispatchEvent(ev testEvent, rep Reporter) {
+	switch ev.Action {
+	case "output":
+		rep.TestOutput(ev.Package, ev.Test, ev.Output)
+	case "run":
+		if ev.Test != "" {
+			rep.TestStarted(ev.Package, ev.Test)
+		}
+	case "pass", "fail", "skip":
+		if ev.Test != "" {
+			rep.TestResult(ev.Package, ev.Test, ev.Action, ev.Elapsed)
+		} else {
+			rep.PackageResult(ev.Package, ev.Action, ev.Elapsed)
+		}
+	}
+}
+
+// multiReporter dispatches every event to each of its Reporters in turn.
+type multiReporter []Reporter
+
+func (m multiReporter) TestStarted(pkg, test string) {
+	for _, r := range m {
+		r.TestStarted(pkg, test)
+	}
+}
+
+func (m multiReporter) TestResult(pkg, test, action string, elapsed float64) {
+	for _, r := range m {
+		r.TestResult(pkg, test, action, elapsed)
+	}
+}
+
+func (m multiReporter) TestOutput(pkg, test, text string) {
+	for _, r := range m {
+		r.TestOutput(pkg, test, text)
+	}
+}
+
+func (m multiReporter) PackageResult(pkg, action string, elapsed float64) {
+	for _, r := range m {
+		r.PackageResult(pkg, action, elapsed)
+	}
+}
+
+func (m multiReporter) RunFinished() {
+	for _, r := range m {
+		r.RunFinished()
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// recordingReporter records every call made to it, for asserting on the
+// events decodeEvents produced from a given input.
+type recordingReporter struct {
+	results  []string
+	outputs  []string
+	finished bool
+}
+
+func (r *recordingReporter) TestStarted(pkg, test string) {}
+
+func (r *recordingReporter) TestResult(pkg, test, action string, elapsed float64) {
+	r.results = append(r.results, "test:"+pkg+":"+test+":"+action)
+}
+
+func (r *recordingReporter) TestOutput(pkg, test, text string) {
+	r.outputs = append(r.outputs, pkg+"|"+text)
+}
+
+func (r *recordingReporter) PackageResult(pkg, action string, elapsed float64) {
+	r.results = append(r.results, "pkg:"+pkg+":"+action)
+}
+
+func (r *recordingReporter) RunFinished() {
+	r.finished = true
+}
+
+// buildFailureFixture is a literal capture of what 'go test -json' prints for
+// a package that fails to build: the JSON stream for any other requested
+// package, interleaved with plain text (not JSON) for the broken one.
+const buildFailureFixture = `# github.com/roastery/autotest/pkgy
+pkgy/foo.go:4:9: undefined: undefinedThing
+FAIL	github.com/roastery/autotest/pkgy [build failed]
+{"Time":"2026-01-01T00:00:00Z","Action":"run","Package":"github.com/roastery/autotest/pkgz","Test":"TestOK"}
+{"Time":"2026-01-01T00:00:00Z","Action":"pass","Package":"github.com/roastery/autotest/pkgz","Test":"TestOK","Elapsed":0}
+{"Time":"2026-01-01T00:00:00Z","Action":"pass","Package":"github.com/roastery/autotest/pkgz","Elapsed":0.01}
+`
+
+func TestDecodeEventsBuildFailure(t *testing.T) {
+	rep := &recordingReporter{}
+	if err := decodeEvents(strings.NewReader(buildFailureFixture), rep); err != nil {
+		t.Fatalf("decodeEvents: %v", err)
+	}
+	if !rep.finished {
+		t.Fatal("RunFinished was never called")
+	}
+
+	wantResults := []string{
+		"pkg:github.com/roastery/autotest/pkgy:fail",
+		"test:github.com/roastery/autotest/pkgz:TestOK:pass",
+		"pkg:github.com/roastery/autotest/pkgz:pass",
+	}
+	if len(rep.results) != len(wantResults) {
+		t.Fatalf("results = %v, want %v", rep.results, wantResults)
+	}
+	for i, want := range wantResults {
+		if rep.results[i] != want {
+			t.Errorf("results[%d] = %q, want %q", i, rep.results[i], want)
+		}
+	}
+
+	const pkg = "github.com/roastery/autotest/pkgy"
+	var found bool
+	for _, o := range rep.outputs {
+		if o == pkg+"|pkgy/foo.go:4:9: undefined: undefinedThing" {
+			found = true
+		}
+		if strings.HasPrefix(o, "|") {
+			t.Errorf("build failure output reported with no package context: %q", o)
+		}
+	}
+	if !found {
+		t.Error("compiler error text for the failed package was not reported under its package")
+	}
+}
+
+// vetFailureFixture is a literal capture of what 'go test -json' prints when
+// a package fails to build because 'go vet' rejects it (e.g. an unused
+// variable): the package header line carries a trailing "[pkg.test]" that a
+// plain compiler build failure doesn't.
+const vetFailureFixture = `# github.com/roastery/autotest/pkgy [github.com/roastery/autotest/pkgy.test]
+pkgy/foo_test.go:6:2: x declared and not used
+FAIL	github.com/roastery/autotest/pkgy [build failed]
+`
+
+func TestDecodeEventsVetFailureKeysOutputByPackage(t *testing.T) {
+	rep := &recordingReporter{}
+	if err := decodeEvents(strings.NewReader(vetFailureFixture), rep); err != nil {
+		t.Fatalf("decodeEvents: %v", err)
+	}
+
+	const pkg = "github.com/roastery/autotest/pkgy"
+	wantLine := pkg + "|pkgy/foo_test.go:6:2: x declared and not used"
+	var found bool
+	for _, o := range rep.outputs {
+		if o == wantLine {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("vet error text not reported under package %q; outputs = %v", pkg, rep.outputs)
+	}
+}
+
+func TestFailLineMatchesRealGoTestOutput(t *testing.T) {
+	cases := []struct {
+		line string
+		pkg  string
+	}{
+		{"FAIL\tgithub.com/roastery/autotest/pkgy [build failed]", "github.com/roastery/autotest/pkgy"},
+		{"FAIL\t_/tmp/foo/pkgy [build failed]", "_/tmp/foo/pkgy"},
+	}
+	for _, c := range cases {
+		m := failLine.FindStringSubmatch(c.line)
+		if m == nil {
+			t.Errorf("failLine did not match %q", c.line)
+			continue
+		}
+		if m[1] != c.pkg {
+			t.Errorf("failLine on %q: package = %q, want %q", c.line, m[1], c.pkg)
+		}
+	}
+}
+
+func TestDecodeEventsIgnoresMalformedOutputWithoutPackage(t *testing.T) {
+	rep := &recordingReporter{}
+	if err := decodeEvents(strings.NewReader("not json at all\n"), rep); err != nil {
+		t.Fatalf("decodeEvents: %v", err)
+	}
+	if !rep.finished {
+		t.Fatal("RunFinished was never called")
+	}
+	if len(rep.outputs) != 1 || rep.outputs[0] != "|not json at all" {
+		t.Errorf("outputs = %v", rep.outputs)
+	}
+}
@@ -0,0 +1,31 @@
+// +build darwin
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// ioctl request numbers for terminal attributes on darwin.
+const (
+	ioctlTIOCGETA = 0x40487413
+	ioctlTIOCSETA = 0x80487414
+)
+
+func tcGetAttr(fd int) (syscall.Termios, error) {
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlTIOCGETA, uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		return t, errno
+	}
+	return t, nil
+}
+
+func tcSetAttr(fd int, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlTIOCSETA, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
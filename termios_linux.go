@@ -0,0 +1,31 @@
+// +build linux
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// ioctl request numbers for terminal attributes on linux.
+const (
+	ioctlTCGETS = 0x5401
+	ioctlTCSETS = 0x5402
+)
+
+func tcGetAttr(fd int) (syscall.Termios, error) {
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlTCGETS, uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		return t, errno
+	}
+	return t, nil
+}
+
+func tcSetAttr(fd int, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlTCSETS, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}